@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksumSpec is the expected digest for a completed download.
+type checksumSpec struct {
+	Algo string // md5, sha1, sha256, or sha512
+	Hex  string
+}
+
+// parseChecksumFlag parses "--checksum sha256:<hex>" into a checksumSpec.
+func parseChecksumFlag(s string) (*checksumSpec, error) {
+	if s == "" {
+		return nil, nil
+	}
+	algo, hexDigest, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, fmt.Errorf("--checksum must be algo:hex, e.g. sha256:abcd...")
+	}
+	if _, err := newHasher(algo); err != nil {
+		return nil, err
+	}
+	return &checksumSpec{Algo: strings.ToLower(algo), Hex: strings.ToLower(hexDigest)}, nil
+}
+
+// parseChecksumFile reads a sha256sum-style file ("<hex>  <filename>" per
+// line, algorithm inferred from digest length) and returns the entry for
+// output's base name. A single-entry file is accepted regardless of name.
+func parseChecksumFile(path, output string) (*checksumSpec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	base := filepath.Base(output)
+	var specs []checksumSpec
+	var matched *checksumSpec
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		digest := fields[0]
+		algo, err := algoForDigestLength(len(digest))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		spec := checksumSpec{Algo: algo, Hex: strings.ToLower(digest)}
+		specs = append(specs, spec)
+		if len(fields) > 1 && strings.TrimPrefix(fields[1], "*") == base {
+			matched = &spec
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if matched != nil {
+		return matched, nil
+	}
+	if len(specs) == 1 {
+		return &specs[0], nil
+	}
+	return nil, fmt.Errorf("%s: no checksum entry for %s", path, base)
+}
+
+// resolveChecksumSpec picks the effective checksum spec from the
+// mutually-exclusive --checksum and --checksum-file flags.
+func resolveChecksumSpec(checksumFlag, checksumFile, output string) (*checksumSpec, error) {
+	if checksumFlag != "" && checksumFile != "" {
+		return nil, fmt.Errorf("--checksum and --checksum-file are mutually exclusive")
+	}
+	if checksumFlag != "" {
+		return parseChecksumFlag(checksumFlag)
+	}
+	if checksumFile != "" {
+		return parseChecksumFile(checksumFile, output)
+	}
+	return nil, nil
+}
+
+func algoForDigestLength(n int) (string, error) {
+	switch n {
+	case 32:
+		return "md5", nil
+	case 40:
+		return "sha1", nil
+	case 64:
+		return "sha256", nil
+	case 128:
+		return "sha512", nil
+	default:
+		return "", fmt.Errorf("unrecognized digest length %d", n)
+	}
+}
+
+func newHasher(algo string) (hash.Hash, error) {
+	switch strings.ToLower(algo) {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm %q", algo)
+	}
+}
+
+// verifyChecksum re-reads the assembled file and compares its digest
+// against spec.
+func verifyChecksum(path string, spec *checksumSpec) (bool, error) {
+	h, err := newHasher(spec.Algo)
+	if err != nil {
+		return false, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return false, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)) == spec.Hex, nil
+}
+
+// checkAndHandleChecksum verifies output against spec, if one was
+// requested. On mismatch it invalidates sc (if the download was resumable)
+// so the next attempt starts fresh, moves the bad file to <output>.corrupt,
+// and returns a non-nil error.
+func checkAndHandleChecksum(output string, spec *checksumSpec, sc *sidecar) error {
+	if spec == nil {
+		return nil
+	}
+
+	ok, err := verifyChecksum(output, spec)
+	if err != nil {
+		return fmt.Errorf("checksum verification failed: %w", err)
+	}
+	if ok {
+		return nil
+	}
+
+	if sc != nil {
+		sc.remove()
+	}
+
+	corrupt := output + ".corrupt"
+	if err := os.Rename(output, corrupt); err != nil {
+		return fmt.Errorf("checksum mismatch for %s (failed to move to %s: %v)", output, corrupt, err)
+	}
+	return fmt.Errorf("checksum mismatch for %s: moved to %s", output, corrupt)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAlgoForDigestLength(t *testing.T) {
+	tests := []struct {
+		n       int
+		want    string
+		wantErr bool
+	}{
+		{32, "md5", false},
+		{40, "sha1", false},
+		{64, "sha256", false},
+		{128, "sha512", false},
+		{10, "", true},
+	}
+
+	for _, tt := range tests {
+		algo, err := algoForDigestLength(tt.n)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("algoForDigestLength(%d) error = %v, wantErr %v", tt.n, err, tt.wantErr)
+		}
+		if algo != tt.want {
+			t.Errorf("algoForDigestLength(%d) = %q, want %q", tt.n, algo, tt.want)
+		}
+	}
+}
+
+func TestParseChecksumFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("single entry regardless of name", func(t *testing.T) {
+		path := filepath.Join(dir, "single.sha256")
+		hexDigest := strings.Repeat("a1", 32)
+		content := hexDigest + "  unrelated-name.bin\n"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		spec, err := parseChecksumFile(path, "output.bin")
+		if err != nil {
+			t.Fatalf("parseChecksumFile: %v", err)
+		}
+		if spec.Algo != "sha256" || spec.Hex != hexDigest {
+			t.Errorf("spec = %+v", spec)
+		}
+	})
+
+	t.Run("matches entry by filename", func(t *testing.T) {
+		path := filepath.Join(dir, "multi.sha256")
+		hexA := strings.Repeat("a1", 32)
+		hexB := strings.Repeat("b2", 32)
+		content := hexA + "  other.bin\n" + hexB + "  output.bin\n"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		spec, err := parseChecksumFile(path, "output.bin")
+		if err != nil {
+			t.Fatalf("parseChecksumFile: %v", err)
+		}
+		if spec.Hex != hexB {
+			t.Errorf("spec.Hex = %q, want %q", spec.Hex, hexB)
+		}
+	})
+
+	t.Run("no match and multiple entries errors", func(t *testing.T) {
+		path := filepath.Join(dir, "nomatch.sha256")
+		hexA := strings.Repeat("a1", 32)
+		hexB := strings.Repeat("b2", 32)
+		content := hexA + "  other.bin\n" + hexB + "  another.bin\n"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseChecksumFile(path, "output.bin"); err == nil {
+			t.Error("expected an error when no entry matches and multiple entries exist")
+		}
+	})
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.bin")
+	if err := os.WriteFile(path, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// sha256("hello world")
+	const want = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+
+	ok, err := verifyChecksum(path, &checksumSpec{Algo: "sha256", Hex: want})
+	if err != nil {
+		t.Fatalf("verifyChecksum: %v", err)
+	}
+	if !ok {
+		t.Error("expected checksum to match")
+	}
+
+	ok, err = verifyChecksum(path, &checksumSpec{Algo: "sha256", Hex: strings.Repeat("0", 64)})
+	if err != nil {
+		t.Fatalf("verifyChecksum: %v", err)
+	}
+	if ok {
+		t.Error("expected checksum mismatch to report false")
+	}
+}
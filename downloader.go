@@ -0,0 +1,345 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"golang.org/x/time/rate"
+)
+
+// progressSink receives byte counts as a download progresses. A
+// *progressbar.ProgressBar already satisfies this, and manifest mode wraps
+// it to also feed an aggregate total line.
+type progressSink interface {
+	Add(n int) error
+	Close() error
+}
+
+// barFactory creates the progress sink for a download once its size is
+// known. The single-URL CLI path hands in a plain standalone bar; manifest
+// mode hands in a per-file line from a shared multiBarPool.
+type barFactory func(size int64, desc string) progressSink
+
+// Downloader drives a single ranged download. It holds the http.Client and
+// worker count instead of package-level state, and takes its progress bar
+// from the barFactory passed to Download, so the same logic can be driven
+// from the single-URL CLI path or a manifest of many files.
+type Downloader struct {
+	Client   *http.Client
+	Workers  int // 0 = pick automatically based on file size
+	Limiter  *rate.Limiter
+	Checksum *checksumSpec // verified against the assembled output, if set
+}
+
+// NewDownloader builds a Downloader with sane defaults for standalone use.
+func NewDownloader(client *http.Client, workers int, limiter *rate.Limiter) *Downloader {
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &Downloader{Client: client, Workers: workers, Limiter: limiter}
+}
+
+// Download fetches one of urls (a file and any failover mirrors of it)
+// into output, splitting it into ranged parts across workers when the
+// server supports it, and reports progress through a bar obtained from
+// newBar once the file size is known.
+func (d *Downloader) Download(urls []string, output string, newBar barFactory) error {
+	primary := urls[0]
+
+	pool, size, etag, lastModified, supportsRange, err := probeMirrors(d.Client, urls)
+	if err != nil {
+		return err
+	}
+
+	if !supportsRange {
+		fmt.Println("Server does not support partial downloads, falling back to single thread...")
+		return d.singleDownload(primary, output, newBar)
+	}
+
+	workers := calculateWorkers(int(size))
+	if d.Workers > 0 {
+		workers = d.Workers
+	}
+
+	scPath := sidecarPath(output)
+	sc, resumed := loadResumableSidecar(scPath, primary, size, etag, lastModified)
+	if resumed {
+		workers = len(sc.Chunks)
+		fmt.Printf("Resuming previous download of %s...\n", output)
+	} else {
+		sc = newSidecar(scPath, primary, size, etag, lastModified, workers)
+	}
+
+	bar := newBar(size, output)
+	defer bar.Close()
+
+	file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := file.Truncate(size); err != nil {
+		return err
+	}
+
+	if err := sc.save(); err != nil {
+		return fmt.Errorf("failed to write sidecar: %w", err)
+	}
+
+	var completed int64
+	for _, c := range sc.Chunks {
+		completed += c.Done
+	}
+	if completed > 0 {
+		bar.Add(int(completed))
+	}
+
+	// A Ctrl-C (or kill) mid-download should still leave a consistent
+	// sidecar behind so the next run can resume cleanly. done is closed
+	// alongside signal.Stop so the goroutine doesn't leak past this call,
+	// e.g. across many Download calls under --manifest.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-sigCh:
+			sc.save()
+			os.Exit(1)
+		case <-done:
+		}
+	}()
+	defer signal.Stop(sigCh)
+	defer close(done)
+
+	var wg sync.WaitGroup
+	var failedMu sync.Mutex
+	var failed []string
+	for i, c := range sc.Chunks {
+		if c.Start+c.Done > c.End {
+			continue // chunk already fully downloaded
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			retries := 0
+			// Parts are assigned round-robin across mirrors; each retry
+			// advances to the next healthy one instead of hammering the
+			// mirror that just failed, and re-reads the chunk's saved
+			// progress so a retry resumes instead of restarting it.
+			for {
+				c := sc.Chunks[i]
+				mirrorURL, mirrorIdx, ok := pool.pickFrom(i + retries)
+				if !ok {
+					msg := fmt.Sprintf("no healthy mirrors left for part %d-%d", c.Start, c.End)
+					fmt.Println("\n" + msg)
+					failedMu.Lock()
+					failed = append(failed, msg)
+					failedMu.Unlock()
+					return
+				}
+				err := d.downloadPart(mirrorURL, output, sc, i, c, bar)
+				if err == nil {
+					return
+				}
+				if isMirrorUnhealthy(err) {
+					pool.markUnhealthy(mirrorIdx)
+				}
+				retries++
+				if retries > maxRetries {
+					msg := fmt.Sprintf("part %d-%d failed after %d retries: %v", c.Start, c.End, maxRetries, err)
+					fmt.Println("\n" + msg)
+					failedMu.Lock()
+					failed = append(failed, msg)
+					failedMu.Unlock()
+					return
+				}
+				fmt.Printf("\nRetrying part %d-%d (attempt %d)...\n", c.Start, c.End, retries)
+				time.Sleep(retryBackoff)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d parts failed:\n%s", len(failed), len(sc.Chunks), strings.Join(failed, "\n"))
+	}
+
+	if err := checkAndHandleChecksum(output, d.Checksum, sc); err != nil {
+		return err
+	}
+
+	if err := sc.remove(); err != nil {
+		fmt.Println("Warning:", err)
+	}
+	return nil
+}
+
+// loadResumableSidecar loads a sidecar for output, if one exists and still
+// matches the requested URL/size/validators, and reports whether it was resumed.
+func loadResumableSidecar(path, url string, size int64, etag, lastModified string) (*sidecar, bool) {
+	sc, err := loadSidecar(path)
+	if err != nil {
+		return nil, false
+	}
+	sc.path = path
+	if !sc.matches(url, size, etag, lastModified) {
+		return nil, false
+	}
+	return sc, true
+}
+
+// newSidecar builds a fresh sidecar with evenly sized chunks.
+func newSidecar(path, url string, size int64, etag, lastModified string, workers int) *sidecar {
+	partSize := size / int64(workers)
+	chunks := make([]chunkState, workers)
+	for i := 0; i < workers; i++ {
+		start := int64(i) * partSize
+		end := start + partSize - 1
+		if i == workers-1 {
+			end = size - 1
+		}
+		chunks[i] = chunkState{Start: start, End: end}
+	}
+	return &sidecar{
+		URL:          url,
+		Output:       strings.TrimSuffix(path, sidecarSuffix),
+		Size:         size,
+		ETag:         etag,
+		LastModified: lastModified,
+		Chunks:       chunks,
+		path:         path,
+	}
+}
+
+// downloadPart fetches a single byte range, resuming from the chunk's last
+// saved offset.
+func (d *Downloader) downloadPart(url, output string, sc *sidecar, chunkIndex int, c chunkState, bar progressSink) error {
+	start := c.Start + c.Done
+	end := c.End
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	rangeHeader := fmt.Sprintf("bytes=%d-%d", start, end)
+	req.Header.Set("Range", rangeHeader)
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return &mirrorUnhealthyErr{fmt.Errorf("server does not support partial content: %d", resp.StatusCode)}
+	}
+
+	file, err := os.OpenFile(output, os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Seek(start, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	body := throttle(resp.Body, d.Limiter)
+
+	done := c.Done
+	var sinceFlush int64
+	buf := make([]byte, readBufSize)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			_, writeErr := file.Write(buf[:n])
+			if writeErr != nil {
+				bar.Add(-int(sinceFlush))
+				return writeErr
+			}
+			bar.Add(n)
+			done += int64(n)
+			sinceFlush += int64(n)
+			if sinceFlush >= flushEvery {
+				sc.updateChunk(chunkIndex, done)
+				sinceFlush = 0
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			// Bytes written since the last flush were already counted into
+			// bar but never persisted to the sidecar, so a retry re-fetches
+			// and re-counts them; undo that overcount here.
+			bar.Add(-int(sinceFlush))
+			return err
+		}
+	}
+	return sc.updateChunk(chunkIndex, done)
+}
+
+// singleDownload is used when the server doesn't support partial content.
+func (d *Downloader) singleDownload(url, output string, newBar barFactory) error {
+	resp, err := d.Client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned status code %d", resp.StatusCode)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	bar := newBar(resp.ContentLength, output)
+	defer bar.Close()
+	body := throttle(resp.Body, d.Limiter)
+	buf := make([]byte, readBufSize)
+	for {
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			if _, err := file.Write(buf[:n]); err != nil {
+				return err
+			}
+			bar.Add(n)
+		}
+		if readErr != nil {
+			if readErr == io.EOF {
+				return checkAndHandleChecksum(output, d.Checksum, nil)
+			}
+			return readErr
+		}
+	}
+}
+
+// standaloneBar is the barFactory used by the single-URL CLI path: one bar,
+// no aggregate line.
+func standaloneBar(size int64, desc string) progressSink {
+	return progressbar.NewOptions64(size,
+		progressbar.OptionSetDescription("Downloading"),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionShowCount(),
+		progressbar.OptionClearOnFinish(),
+	)
+}
@@ -3,26 +3,25 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
-	"sync"
 	"time"
-
-	"github.com/schollz/progressbar/v3"
 )
 
 const (
 	maxRetries   = 3
 	retryBackoff = 2 * time.Second
-)
 
-var (
-	bar *progressbar.ProgressBar
+	// flushEvery controls how many bytes a worker downloads before it
+	// persists its chunk progress to the sidecar manifest.
+	flushEvery = 4 * 1024 * 1024
+
+	// readBufSize is the buffer size used to stream response bodies to disk.
+	readBufSize = 32 * 1024
+
+	defaultMaxConcurrentFiles = 20
 )
 
 func main() {
@@ -45,226 +44,114 @@ func main() {
 
 // download command
 func downloadCmd(args []string) {
-	if len(args) < 1 {
-		fmt.Println("Usage: sdm download <url> [--output file] [--worker n]")
-		os.Exit(1)
+	// A manifest-driven invocation has no positional URL, just flags. Any
+	// other leading non-flag args are treated as mirror URLs for the same file.
+	var urls []string
+	lead := 0
+	for lead < len(args) && !strings.HasPrefix(args[lead], "-") {
+		lead++
 	}
-
-	url := args[0]
-	defaultFileName := getFileNameFromURL(url)
+	urls = append(urls, args[:lead]...)
+	flagArgs := args[lead:]
 
 	fs := flag.NewFlagSet("download", flag.ExitOnError)
-	output := fs.String("output", defaultFileName, "specify output location")
+	output := fs.String("output", "", "specify output location")
 	workersFlag := fs.Int("worker", 0, "override number of workers")
-	fs.Parse(args[1:])
-
-	// If output is a directory, append filename
-	fi, err := os.Stat(*output)
-	if err == nil && fi.IsDir() {
-		*output = filepath.Join(*output, defaultFileName)
-	}
-
-	startTime := time.Now()
-
-	err = downloadFile(url, *output, *workersFlag)
+	rateFlag := fs.String("rate", "0", "cap total download throughput, e.g. 2MB, 500KB (0 = unlimited)")
+	manifest := fs.String("manifest", "", "path to a manifest of url/output pairs to download concurrently")
+	maxConcurrentFiles := fs.Int("max-concurrent-files", defaultMaxConcurrentFiles, "max files downloaded at once in --manifest mode")
+	proxyFlag := fs.String("proxy", "", "HTTP/HTTPS/SOCKS5 proxy URL, e.g. socks5://127.0.0.1:1080")
+	insecure := fs.Bool("insecure", false, "skip TLS certificate verification")
+	var headers headerFlag
+	fs.Var(&headers, "header", "extra request header as K:V (repeatable)")
+	resolve := resolveFlag{}
+	fs.Var(resolve, "resolve", "pin host:ip for DNS resolution, e.g. example.com:1.2.3.4 (repeatable)")
+	checksumFlag := fs.String("checksum", "", "verify output against algo:hex, e.g. sha256:abcd... (md5/sha1/sha256/sha512)")
+	checksumFile := fs.String("checksum-file", "", "verify output against a checksum-file entry matching its filename")
+	var mirrorFlag mirrorListFlag
+	fs.Var(&mirrorFlag, "mirror", "additional mirror URL serving the same file (repeatable)")
+
+	fs.Parse(flagArgs)
+	urls = append(urls, mirrorFlag...)
+
+	rateLimit, err := parseRate(*rateFlag)
 	if err != nil {
-		fmt.Println("\nDownload failed:", err)
+		fmt.Println("Invalid --rate value:", err)
 		os.Exit(1)
-	} else {
-		elapsed := time.Since(startTime)
-
-		info, err := os.Stat(*output)
-		if err != nil {
-			fmt.Println("Error getting downloaded file size:", err)
-			return
-		}
-		size := info.Size()
-		speed := float64(size) / elapsed.Seconds()
-
-		fmt.Println("\nDownload completed successfully!")
-		fmt.Printf("Downloaded in: %s\n", elapsed.Round(time.Millisecond))
-		fmt.Printf("Average speed: %s/s\n", formatSpeed(speed))
 	}
-}
-
-// download file
-func downloadFile(url, output string, workersOverride int) error {
-	client := &http.Client{}
+	limiter := newRateLimiter(rateLimit)
 
-	// Perform a GET request for bytes 0-0 to detect size and partial support
-	req, err := http.NewRequest("GET", url, nil)
+	client, err := newHTTPClient(transportConfig{
+		Proxy:    *proxyFlag,
+		Insecure: *insecure,
+		Headers:  headers,
+		Resolve:  resolve,
+	})
 	if err != nil {
-		return err
-	}
-	req.Header.Set("Range", "bytes=0-0")
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusPartialContent {
-		fmt.Println("Server does not support partial downloads, falling back to single thread...")
-		return singleDownload(url, output)
-	}
-
-	contentRange := resp.Header.Get("Content-Range")
-	if contentRange == "" {
-		return fmt.Errorf("missing Content-Range header")
-	}
-	parts := strings.Split(contentRange, "/")
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid Content-Range format")
-	}
-	size, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return fmt.Errorf("invalid content length: %v", err)
-	}
-
-	fmt.Printf("File size: %d bytes\n", size)
-
-	workers := calculateWorkers(size)
-	if workersOverride > 0 {
-		workers = workersOverride
+		fmt.Println("Invalid transport configuration:", err)
+		os.Exit(1)
 	}
-	fmt.Printf("Using %d workers...\n", workers)
 
-	file, err := os.Create(output)
-	if err != nil {
-		return err
+	if *manifest != "" {
+		startTime := time.Now()
+		if err := runManifest(*manifest, *maxConcurrentFiles, *workersFlag, limiter, client, resolve); err != nil {
+			fmt.Println("\nManifest download failed:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nManifest completed in %s\n", time.Since(startTime).Round(time.Millisecond))
+		return
 	}
-	defer file.Close()
 
-	err = file.Truncate(int64(size))
-	if err != nil {
-		return err
+	if len(urls) == 0 {
+		fmt.Println("Usage: sdm download <url> [<mirror-url> ...] [--output file] [--worker n] [--rate 2MB] [--checksum sha256:hex]")
+		fmt.Println("       sdm download --manifest urls.txt [--max-concurrent-files n]")
+		os.Exit(1)
 	}
 
-	bar = progressbar.NewOptions(size,
-		progressbar.OptionSetDescription("Downloading"),
-		progressbar.OptionShowBytes(true),
-		progressbar.OptionSetWidth(40),
-		progressbar.OptionThrottle(100*time.Millisecond),
-		progressbar.OptionShowCount(),
-		progressbar.OptionClearOnFinish(),
-	)
-	defer bar.Close()
-
-	partSize := size / workers
-	var wg sync.WaitGroup
-	wg.Add(workers)
-
-	for i := 0; i < workers; i++ {
-		start := i * partSize
-		end := start + partSize - 1
-		if i == workers-1 {
-			end = size - 1
-		}
-
-		go func(start, end int) {
-			defer wg.Done()
-			retries := 0
-			for {
-				err := downloadPart(client, url, output, start, end)
-				if err == nil {
-					break
-				}
-				retries++
-				if retries > maxRetries {
-					fmt.Printf("\nFailed to download part %d-%d after %d retries: %v\n", start, end, maxRetries, err)
-					break
-				}
-				fmt.Printf("\nRetrying part %d-%d (attempt %d)...\n", start, end, retries)
-				time.Sleep(retryBackoff)
-			}
-		}(start, end)
+	defaultFileName := getFileNameFromURL(urls[0])
+	if *output == "" {
+		*output = defaultFileName
 	}
 
-	wg.Wait()
-	return nil
-}
-
-// download part of the file
-func downloadPart(client *http.Client, url, output string, start, end int) error {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return err
+	// If output is a directory, append filename
+	fi, err := os.Stat(*output)
+	if err == nil && fi.IsDir() {
+		*output = filepath.Join(*output, defaultFileName)
 	}
 
-	rangeHeader := fmt.Sprintf("bytes=%d-%d", start, end)
-	req.Header.Set("Range", rangeHeader)
-
-	resp, err := client.Do(req)
+	checksum, err := resolveChecksumSpec(*checksumFlag, *checksumFile, *output)
 	if err != nil {
-		return err
+		fmt.Println("Invalid checksum configuration:", err)
+		os.Exit(1)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusPartialContent {
-		return fmt.Errorf("server does not support partial content: %d", resp.StatusCode)
+	for _, u := range urls {
+		preResolveHost(u, resolve)
 	}
 
-	file, err := os.OpenFile(output, os.O_WRONLY, 0666)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+	startTime := time.Now()
 
-	_, err = file.Seek(int64(start), io.SeekStart)
+	d := NewDownloader(client, *workersFlag, limiter)
+	d.Checksum = checksum
+	err = d.Download(urls, *output, standaloneBar)
 	if err != nil {
-		return err
-	}
+		fmt.Println("\nDownload failed:", err)
+		os.Exit(1)
+	} else {
+		elapsed := time.Since(startTime)
 
-	buf := make([]byte, 32*1024)
-	for {
-		n, err := resp.Body.Read(buf)
-		if n > 0 {
-			_, writeErr := file.Write(buf[:n])
-			if writeErr != nil {
-				return writeErr
-			}
-			bar.Add(n)
-		}
+		info, err := os.Stat(*output)
 		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return err
+			fmt.Println("Error getting downloaded file size:", err)
+			return
 		}
-	}
-	return nil
-}
-
-// single download if server does not support partial content
-func singleDownload(url, output string) error {
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("server returned status code %d", resp.StatusCode)
-	}
+		size := info.Size()
+		speed := float64(size) / elapsed.Seconds()
 
-	file, err := os.Create(output)
-	if err != nil {
-		return err
+		fmt.Println("\nDownload completed successfully!")
+		fmt.Printf("Downloaded in: %s\n", elapsed.Round(time.Millisecond))
+		fmt.Printf("Average speed: %s/s\n", formatSpeed(speed))
 	}
-	defer file.Close()
-
-	bar = progressbar.NewOptions64(resp.ContentLength,
-		progressbar.OptionSetDescription("Downloading"),
-		progressbar.OptionShowBytes(true),
-		progressbar.OptionSetWidth(40),
-		progressbar.OptionThrottle(100*time.Millisecond),
-		progressbar.OptionShowCount(),
-		progressbar.OptionClearOnFinish(),
-	)
-	defer bar.Close()
-
-	_, err = io.Copy(io.MultiWriter(file, bar), resp.Body)
-	return err
 }
 
 // calculate workers based on file size
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// manifestEntry is one URL/destination pair read from a manifest file.
+type manifestEntry struct {
+	URL    string `json:"url"`
+	Output string `json:"output"`
+}
+
+// parseManifest reads either a JSON array of {"url","output"} objects or a
+// plain text file of "<url>[ <output>]" lines, one per download.
+func parseManifest(path string) ([]manifestEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		var entries []manifestEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("invalid manifest JSON: %w", err)
+		}
+		for i, entry := range entries {
+			if entry.Output == "" {
+				entries[i].Output = getFileNameFromURL(entry.URL)
+			}
+		}
+		return entries, nil
+	}
+
+	var entries []manifestEntry
+	scanner := bufio.NewScanner(strings.NewReader(trimmed))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		entry := manifestEntry{URL: fields[0]}
+		if len(fields) > 1 {
+			entry.Output = fields[1]
+		} else {
+			entry.Output = getFileNameFromURL(fields[0])
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// runManifest downloads every entry in the manifest at path, capping the
+// number of files in flight at maxConcurrentFiles while still splitting
+// each file into ranged parts across its own workers.
+func runManifest(path string, maxConcurrentFiles, workersOverride int, limiter *rate.Limiter, client *http.Client, resolve resolveFlag) error {
+	entries, err := parseManifest(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("manifest %s contains no downloads", path)
+	}
+
+	for _, entry := range entries {
+		preResolveHost(entry.URL, resolve)
+	}
+
+	pool := newMultiBarPool(os.Stdout)
+
+	sem := make(chan struct{}, maxConcurrentFiles)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failures []string
+
+	for _, entry := range entries {
+		entry := entry
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			d := NewDownloader(client, workersOverride, limiter)
+			if err := d.Download([]string{entry.URL}, entry.Output, pool.newBar); err != nil {
+				mu.Lock()
+				failures = append(failures, fmt.Sprintf("%s: %v", entry.URL, err))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	pool.aggregate.Close()
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d downloads failed:\n%s", len(failures), len(entries), strings.Join(failures, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseManifestJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "manifest.json")
+	content := `[{"url": "https://host/a.bin", "output": "a.bin"}, {"url": "https://host/b.bin"}]`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := parseManifest(path)
+	if err != nil {
+		t.Fatalf("parseManifest: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if entries[0].Output != "a.bin" {
+		t.Errorf("entries[0].Output = %q, want %q", entries[0].Output, "a.bin")
+	}
+	// A JSON entry that omits "output" should fall back to the URL's
+	// basename, same as the plain-text format does.
+	if entries[1].Output != "b.bin" {
+		t.Errorf("entries[1].Output = %q, want %q (derived from URL)", entries[1].Output, "b.bin")
+	}
+}
+
+func TestParseManifestTextFormat(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "manifest.txt")
+	content := "# a comment\nhttps://host/a.bin custom.bin\n\nhttps://host/b.bin\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := parseManifest(path)
+	if err != nil {
+		t.Fatalf("parseManifest: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if entries[0].URL != "https://host/a.bin" || entries[0].Output != "custom.bin" {
+		t.Errorf("entries[0] = %+v", entries[0])
+	}
+	if entries[1].URL != "https://host/b.bin" || entries[1].Output != "b.bin" {
+		t.Errorf("entries[1] = %+v", entries[1])
+	}
+}
+
+func TestParseManifestInvalidJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "manifest.json")
+	if err := os.WriteFile(path, []byte("[not valid json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseManifest(path); err == nil {
+		t.Error("expected an error for malformed manifest JSON")
+	}
+}
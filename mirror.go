@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// mirrorUnhealthyErr marks a mirror as having failed in a way that should
+// take it out of rotation for the rest of the run (bad status code, size
+// mismatch), as opposed to a transient network error worth simply retrying.
+type mirrorUnhealthyErr struct {
+	err error
+}
+
+func (e *mirrorUnhealthyErr) Error() string { return e.err.Error() }
+func (e *mirrorUnhealthyErr) Unwrap() error { return e.err }
+
+// mirrorListFlag collects repeatable "--mirror url" flags.
+type mirrorListFlag []string
+
+func (m *mirrorListFlag) String() string {
+	if m == nil {
+		return ""
+	}
+	return strings.Join(*m, ",")
+}
+
+func (m *mirrorListFlag) Set(v string) error {
+	*m = append(*m, v)
+	return nil
+}
+
+// mirrorPool round-robins parts across a set of mirror URLs serving the
+// same file, excluding any mirror that turns out to be unhealthy.
+type mirrorPool struct {
+	mu      sync.Mutex
+	urls    []string
+	healthy []bool
+}
+
+func newMirrorPool(urls []string) *mirrorPool {
+	healthy := make([]bool, len(urls))
+	for i := range healthy {
+		healthy[i] = true
+	}
+	return &mirrorPool{urls: urls, healthy: healthy}
+}
+
+func (p *mirrorPool) len() int {
+	return len(p.urls)
+}
+
+// pickFrom returns the first healthy mirror at or after index start
+// (wrapping around), so repeated calls with increasing start values rotate
+// through mirrors instead of hammering the same one.
+func (p *mirrorPool) pickFrom(start int) (url string, index int, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := len(p.urls)
+	for i := 0; i < n; i++ {
+		idx := (start + i) % n
+		if p.healthy[idx] {
+			return p.urls[idx], idx, true
+		}
+	}
+	return "", -1, false
+}
+
+func (p *mirrorPool) markUnhealthy(index int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if index >= 0 && index < len(p.healthy) && p.healthy[index] {
+		p.healthy[index] = false
+		fmt.Printf("Mirror %s marked unhealthy, excluding from rotation\n", p.urls[index])
+	}
+}
+
+// probeOne issues a bytes=0-0 range probe. probeMirrors runs it on the
+// primary URL and on every mirror alike, so mirrors are validated exactly
+// the way range support is detected for a single URL.
+func probeOne(client *http.Client, url string) (size int64, etag, lastModified string, supportsRange bool, err error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, "", "", false, err
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, "", "", false, nil
+	}
+
+	contentRange := resp.Header.Get("Content-Range")
+	parts := strings.Split(contentRange, "/")
+	if len(parts) != 2 {
+		return 0, "", "", false, fmt.Errorf("invalid Content-Range format from %s", url)
+	}
+	size, err = strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, "", "", false, fmt.Errorf("invalid content length from %s: %w", url, err)
+	}
+	return size, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"), true, nil
+}
+
+// probeMirrors validates every mirror against the primary (urls[0]): same
+// size, and matching ETag when both sides provide one. A mirror that
+// disagrees is excluded from the pool up front rather than failing the run.
+func probeMirrors(client *http.Client, urls []string) (pool *mirrorPool, size int64, etag, lastModified string, supportsRange bool, err error) {
+	pool = newMirrorPool(urls)
+
+	size, etag, lastModified, supportsRange, err = probeOne(client, urls[0])
+	if err != nil {
+		return nil, 0, "", "", false, err
+	}
+	if !supportsRange || len(urls) == 1 {
+		return pool, size, etag, lastModified, supportsRange, nil
+	}
+
+	for i := 1; i < len(urls); i++ {
+		mSize, mETag, _, mSupports, mErr := probeOne(client, urls[i])
+		switch {
+		case mErr != nil || !mSupports:
+			fmt.Printf("Mirror %s is not usable, excluding from rotation\n", urls[i])
+			pool.markUnhealthy(i)
+		case mSize != size:
+			fmt.Printf("Mirror %s reports a different size, excluding from rotation\n", urls[i])
+			pool.markUnhealthy(i)
+		case etag != "" && mETag != "" && mETag != etag:
+			fmt.Printf("Mirror %s reports a different ETag, excluding from rotation\n", urls[i])
+			pool.markUnhealthy(i)
+		}
+	}
+	return pool, size, etag, lastModified, supportsRange, nil
+}
+
+// isMirrorUnhealthy reports whether err should take its mirror out of rotation.
+func isMirrorUnhealthy(err error) bool {
+	var unhealthy *mirrorUnhealthyErr
+	return errors.As(err, &unhealthy)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMirrorPoolPickFrom(t *testing.T) {
+	pool := newMirrorPool([]string{"a", "b", "c"})
+
+	url, idx, ok := pool.pickFrom(0)
+	if !ok || url != "a" || idx != 0 {
+		t.Fatalf("pickFrom(0) = %q, %d, %v, want a, 0, true", url, idx, ok)
+	}
+
+	// Rotates to the next mirror as start advances.
+	url, idx, ok = pool.pickFrom(1)
+	if !ok || url != "b" || idx != 1 {
+		t.Fatalf("pickFrom(1) = %q, %d, %v, want b, 1, true", url, idx, ok)
+	}
+
+	// Skips unhealthy entries.
+	pool.markUnhealthy(1)
+	url, idx, ok = pool.pickFrom(1)
+	if !ok || url != "c" || idx != 2 {
+		t.Fatalf("pickFrom(1) after marking b unhealthy = %q, %d, %v, want c, 2, true", url, idx, ok)
+	}
+
+	pool.markUnhealthy(0)
+	pool.markUnhealthy(2)
+	if _, _, ok := pool.pickFrom(0); ok {
+		t.Error("pickFrom should report ok=false once every mirror is unhealthy")
+	}
+}
+
+func TestProbeMirrorsSizeMismatch(t *testing.T) {
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes 0-0/1000")
+		w.Header().Set("ETag", `"abc"`)
+		w.WriteHeader(http.StatusPartialContent)
+	}))
+	defer good.Close()
+
+	mismatched := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes 0-0/2000")
+		w.WriteHeader(http.StatusPartialContent)
+	}))
+	defer mismatched.Close()
+
+	pool, size, _, _, supportsRange, err := probeMirrors(http.DefaultClient, []string{good.URL, mismatched.URL})
+	if err != nil {
+		t.Fatalf("probeMirrors: %v", err)
+	}
+	if !supportsRange || size != 1000 {
+		t.Fatalf("supportsRange=%v size=%d, want true, 1000", supportsRange, size)
+	}
+
+	// pickFrom wraps around to the only remaining healthy mirror (index 0)
+	// rather than returning the excluded one.
+	if url, idx, ok := pool.pickFrom(1); !ok || idx != 0 || url != good.URL {
+		t.Errorf("pickFrom(1) = %q, %d, %v, want the primary mirror at index 0", url, idx, ok)
+	}
+	if url, _, ok := pool.pickFrom(0); !ok || url != good.URL {
+		t.Errorf("expected the primary mirror still healthy, got %q, %v", url, ok)
+	}
+}
+
+func TestIsMirrorUnhealthy(t *testing.T) {
+	if isMirrorUnhealthy(nil) {
+		t.Error("nil error should not be considered a mirror-unhealthy error")
+	}
+	wrapped := &mirrorUnhealthyErr{err: http.ErrBodyNotAllowed}
+	if !isMirrorUnhealthy(wrapped) {
+		t.Error("expected a *mirrorUnhealthyErr to be reported unhealthy")
+	}
+}
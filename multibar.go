@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// multiBarPool renders one progress line per concurrently downloading file
+// plus a trailing aggregate line, working around progressbar/v3 only
+// natively driving a single live bar.
+type multiBarPool struct {
+	mu        sync.Mutex
+	out       io.Writer
+	lines     int
+	aggregate *progressbar.ProgressBar
+}
+
+// newMultiBarPool starts a pool with an indeterminate aggregate line; the
+// manifest driver doesn't know the combined size of every file up front.
+func newMultiBarPool(out io.Writer) *multiBarPool {
+	p := &multiBarPool{out: out}
+	p.aggregate = progressbar.NewOptions64(-1,
+		progressbar.OptionSetDescription("Total"),
+		progressbar.OptionSetWriter(out),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionShowCount(),
+	)
+	p.lines = 1 // the aggregate line itself
+	return p
+}
+
+// newBar allocates a new line-scoped progress sink for a single file,
+// reported to the pool's aggregate line as it progresses.
+func (p *multiBarPool) newBar(size int64, desc string) progressSink {
+	p.mu.Lock()
+	line := p.lines
+	p.lines++
+	p.mu.Unlock()
+
+	fmt.Fprintln(p.out) // reserve this file's line below the existing ones
+
+	w := &poolLineWriter{pool: p, line: line}
+	bar := progressbar.NewOptions64(size,
+		progressbar.OptionSetDescription(desc),
+		progressbar.OptionSetWriter(w),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionSetWidth(30),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionShowCount(),
+		progressbar.OptionClearOnFinish(),
+	)
+	return &multiSink{bar: bar, pool: p}
+}
+
+// multiSink fans progress out to both a file's own line and the pool's
+// shared aggregate line.
+type multiSink struct {
+	bar  *progressbar.ProgressBar
+	pool *multiBarPool
+}
+
+func (s *multiSink) Add(n int) error {
+	s.pool.aggregate.Add(n)
+	return s.bar.Add(n)
+}
+
+func (s *multiSink) Close() error {
+	return s.bar.Close()
+}
+
+// poolLineWriter pins a bar's render to a fixed terminal row among the
+// pool's stacked lines, so concurrent renders don't clobber each other.
+type poolLineWriter struct {
+	pool *multiBarPool
+	line int
+}
+
+func (w *poolLineWriter) Write(b []byte) (int, error) {
+	w.pool.mu.Lock()
+	defer w.pool.mu.Unlock()
+
+	rows := w.pool.lines - w.line
+	fmt.Fprintf(w.pool.out, "\033[%dA\r\033[2K", rows)
+	n, err := w.pool.out.Write(b)
+	fmt.Fprintf(w.pool.out, "\033[%dB", rows)
+	return n, err
+}
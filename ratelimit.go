@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"io"
+
+	"code.cloudfoundry.org/bytefmt"
+	"golang.org/x/time/rate"
+)
+
+// parseRate parses a human-readable throughput cap such as "2MB" or "500KB"
+// into bytes per second. "0" (or an empty string) means unlimited.
+func parseRate(s string) (int, error) {
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+	n, err := bytefmt.ToBytes(s)
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// newRateLimiter builds a shared token-bucket limiter capped at bytesPerSec.
+// A limit of 0 means unlimited, in which case nil is returned.
+func newRateLimiter(bytesPerSec int) *rate.Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	// Burst must cover a single Read's worth of bytes (readBufSize) or
+	// WaitN rejects it outright, so a cap smaller than the read buffer
+	// raises burst to readBufSize; the rate itself still throttles.
+	burst := bytesPerSec
+	if burst < readBufSize {
+		burst = readBufSize
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSec), burst)
+}
+
+// limitedReader wraps an io.Reader and throttles Read calls against a shared
+// token-bucket limiter, so aggregate throughput across all workers stays
+// under the configured cap rather than each worker getting its own budget.
+type limitedReader struct {
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+// throttle wraps r with limiter, if one is configured.
+func throttle(r io.Reader, limiter *rate.Limiter) io.Reader {
+	if limiter == nil {
+		return r
+	}
+	return &limitedReader{r: r, limiter: limiter}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	if n > 0 {
+		if waitErr := l.limiter.WaitN(context.Background(), n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"zero string", "0", 0, false},
+		{"empty string", "", 0, false},
+		{"megabytes", "2MB", 2 * 1024 * 1024, false},
+		{"kilobytes", "500KB", 500 * 1024, false},
+		{"invalid", "not-a-rate", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRate(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRate(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("parseRate(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRateLimiterBurstCoversReadBuffer(t *testing.T) {
+	// Regression test: a rate smaller than readBufSize must still allow a
+	// single full-buffer Read through, rather than WaitN rejecting it for
+	// exceeding burst.
+	limiter := newRateLimiter(10 * 1024)
+	if limiter == nil {
+		t.Fatal("newRateLimiter returned nil for a positive rate")
+	}
+	if limiter.Burst() < readBufSize {
+		t.Errorf("Burst() = %d, want at least readBufSize (%d)", limiter.Burst(), readBufSize)
+	}
+}
+
+func TestLimitedReaderPassesFullBufferBelowRate(t *testing.T) {
+	data := make([]byte, readBufSize)
+	limiter := newRateLimiter(10 * 1024) // below readBufSize, regression case
+
+	r := throttle(bytes.NewReader(data), limiter)
+	buf := make([]byte, readBufSize)
+	n, err := r.Read(buf)
+	if err != nil && err != io.EOF {
+		t.Fatalf("Read returned unexpected error: %v", err)
+	}
+	if n != readBufSize {
+		t.Errorf("Read returned %d bytes, want %d", n, readBufSize)
+	}
+}
+
+func TestThrottleNilLimiterIsNoop(t *testing.T) {
+	r := bytes.NewReader([]byte("hello"))
+	if throttle(r, nil) != io.Reader(r) {
+		t.Error("throttle with a nil limiter should return the original reader unchanged")
+	}
+}
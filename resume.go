@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// sidecarSuffix is appended to the output path to store resume state.
+const sidecarSuffix = ".sdmpart"
+
+// chunkState tracks progress for a single byte range of the download.
+type chunkState struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Done  int64 `json:"done"` // bytes completed within this chunk
+}
+
+// sidecar is the on-disk manifest that allows a download to be resumed.
+type sidecar struct {
+	URL          string       `json:"url"`
+	Output       string       `json:"output"`
+	Size         int64        `json:"size"`
+	ETag         string       `json:"etag,omitempty"`
+	LastModified string       `json:"last_modified,omitempty"`
+	Chunks       []chunkState `json:"chunks"`
+
+	mu   sync.Mutex `json:"-"`
+	path string     `json:"-"`
+}
+
+// sidecarPath returns the path of the resume manifest for a given output file.
+func sidecarPath(output string) string {
+	return output + sidecarSuffix
+}
+
+// loadSidecar reads and parses a resume manifest, if present.
+func loadSidecar(path string) (*sidecar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sc sidecar
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return nil, err
+	}
+	sc.path = path
+	return &sc, nil
+}
+
+// matches reports whether an existing sidecar is still valid for the
+// current download request, i.e. the server is serving the same resource.
+func (sc *sidecar) matches(url string, size int64, etag, lastModified string) bool {
+	if sc.URL != url || sc.Size != size {
+		return false
+	}
+	// A validator both sides carry (ETag first, then Last-Modified) must
+	// still match. If they share none, the sidecar matches only when neither
+	// side has any validator, so URL and size alone decide.
+	if etag != "" && sc.ETag != "" {
+		return etag == sc.ETag
+	}
+	if lastModified != "" && sc.LastModified != "" {
+		return lastModified == sc.LastModified
+	}
+	return etag == "" && lastModified == "" && sc.ETag == "" && sc.LastModified == ""
+}
+
+// save atomically flushes the sidecar to disk (write to a temp file, then
+// rename), so a crash mid-write never leaves a corrupt manifest.
+func (sc *sidecar) save() error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	data, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+
+	tmp := sc.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, sc.path)
+}
+
+// updateChunk records progress for chunk i and flushes to disk.
+func (sc *sidecar) updateChunk(i int, done int64) error {
+	sc.mu.Lock()
+	sc.Chunks[i].Done = done
+	sc.mu.Unlock()
+	return sc.save()
+}
+
+// remove deletes the sidecar file; called once the download completes cleanly.
+func (sc *sidecar) remove() error {
+	err := os.Remove(sc.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove sidecar %s: %w", sc.path, err)
+	}
+	return nil
+}
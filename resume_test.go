@@ -0,0 +1,93 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSidecarMatches(t *testing.T) {
+	tests := []struct {
+		name                     string
+		scURL, scETag, scLastMod string
+		scSize                   int64
+		url, etag, lastMod       string
+		size                     int64
+		want                     bool
+	}{
+		{"identical url/size/etag", "u", "v1", "", 100, "u", "v1", "", 100, true},
+		{"different url", "u", "v1", "", 100, "other", "v1", "", 100, false},
+		{"different size", "u", "v1", "", 100, "u", "v1", "", 200, false},
+		{"etag mismatch", "u", "v1", "", 100, "u", "v2", "", 100, false},
+		{"last-modified match, no etag", "u", "", "mon", 100, "u", "", "mon", 100, true},
+		{"last-modified mismatch", "u", "", "mon", 100, "u", "", "tue", 100, false},
+		{"no validators on either side", "u", "", "", 100, "u", "", "", 100, true},
+		{"server now has a validator sidecar lacks", "u", "", "", 100, "u", "v1", "", 100, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := &sidecar{URL: tt.scURL, Size: tt.scSize, ETag: tt.scETag, LastModified: tt.scLastMod}
+			got := sc.matches(tt.url, tt.size, tt.etag, tt.lastMod)
+			if got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSidecarSaveLoadRoundtrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.sdmpart")
+
+	sc := &sidecar{
+		URL:    "http://example.com/f",
+		Output: "out",
+		Size:   1000,
+		ETag:   "abc",
+		Chunks: []chunkState{{Start: 0, End: 499}, {Start: 500, End: 999}},
+		path:   path,
+	}
+	if err := sc.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := loadSidecar(path)
+	if err != nil {
+		t.Fatalf("loadSidecar: %v", err)
+	}
+	if loaded.URL != sc.URL || loaded.Size != sc.Size || loaded.ETag != sc.ETag {
+		t.Errorf("loaded sidecar = %+v, want matching %+v", loaded, sc)
+	}
+	if len(loaded.Chunks) != 2 || loaded.Chunks[1].Start != 500 {
+		t.Errorf("loaded chunks = %+v", loaded.Chunks)
+	}
+}
+
+func TestSidecarUpdateChunkPersists(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.sdmpart")
+
+	sc := &sidecar{
+		URL:    "http://example.com/f",
+		Size:   1000,
+		Chunks: []chunkState{{Start: 0, End: 999}},
+		path:   path,
+	}
+	if err := sc.updateChunk(0, 250); err != nil {
+		t.Fatalf("updateChunk: %v", err)
+	}
+
+	loaded, err := loadSidecar(path)
+	if err != nil {
+		t.Fatalf("loadSidecar: %v", err)
+	}
+	if loaded.Chunks[0].Done != 250 {
+		t.Errorf("Chunks[0].Done = %d, want 250", loaded.Chunks[0].Done)
+	}
+}
+
+func TestSidecarPath(t *testing.T) {
+	if got := sidecarPath("out.bin"); got != "out.bin.sdmpart" {
+		t.Errorf("sidecarPath() = %q, want %q", got, "out.bin.sdmpart")
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// transportConfig holds the CLI-configurable pieces of the HTTP transport
+// shared by every worker, so sdm only ever builds one http.Client per run.
+type transportConfig struct {
+	Proxy    string
+	Insecure bool
+	Headers  headerFlag
+	Resolve  resolveFlag
+}
+
+// headerFlag collects repeatable "--header K:V" flags.
+type headerFlag []string
+
+func (h *headerFlag) String() string {
+	if h == nil {
+		return ""
+	}
+	return strings.Join(*h, ",")
+}
+
+func (h *headerFlag) Set(v string) error {
+	if !strings.Contains(v, ":") {
+		return fmt.Errorf("header %q must be in K:V form", v)
+	}
+	*h = append(*h, v)
+	return nil
+}
+
+// resolveFlag collects repeatable "--resolve host:ip" overrides.
+type resolveFlag map[string]string
+
+func (r resolveFlag) String() string {
+	parts := make([]string, 0, len(r))
+	for host, ip := range r {
+		parts = append(parts, host+":"+ip)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r resolveFlag) Set(v string) error {
+	host, ip, ok := strings.Cut(v, ":")
+	if !ok || host == "" || ip == "" {
+		return fmt.Errorf("--resolve value %q must be host:ip", v)
+	}
+	r[host] = ip
+	return nil
+}
+
+// newHTTPClient builds a single http.Client, configured from cfg, that is
+// reused across every worker goroutine instead of dialing a fresh client
+// per request.
+func newHTTPClient(cfg transportConfig) (*http.Client, error) {
+	dial := (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+
+	if len(cfg.Resolve) > 0 {
+		next := dial
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(addr)
+			if err == nil {
+				if ip, ok := cfg.Resolve[host]; ok {
+					addr = net.JoinHostPort(ip, port)
+				}
+			}
+			return next(ctx, network, addr)
+		}
+	}
+
+	transport := &http.Transport{
+		DialContext:         dial,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 32,
+		IdleConnTimeout:     90 * time.Second,
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: cfg.Insecure},
+	}
+
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --proxy: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "socks5", "socks5h":
+			dialer, err := proxy.FromURL(proxyURL, proxy.Direct)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SOCKS5 --proxy: %w", err)
+			}
+			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.Dial(network, addr)
+			}
+		default:
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+
+	var rt http.RoundTripper = transport
+	if len(cfg.Headers) > 0 {
+		rt = &headerRoundTripper{headers: cfg.Headers, next: rt}
+	}
+
+	return &http.Client{Transport: rt}, nil
+}
+
+// headerRoundTripper injects a fixed set of "K:V" headers into every
+// outgoing request, letting users authenticate against servers that
+// require cookies or bearer tokens.
+type headerRoundTripper struct {
+	headers headerFlag
+	next    http.RoundTripper
+}
+
+func (rt *headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for _, h := range rt.headers {
+		key, value, _ := strings.Cut(h, ":")
+		req.Header.Set(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+	return rt.next.RoundTrip(req)
+}
+
+// preResolveHost resolves rawURL's host once up front (unless it's already
+// pinned via --resolve) and logs the chosen addresses, mainly so users can
+// confirm which mirror/IP a download actually hit.
+func preResolveHost(rawURL string, overrides resolveFlag) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+	host := u.Hostname()
+	if host == "" {
+		return
+	}
+	if ip, ok := overrides[host]; ok {
+		fmt.Printf("Resolving %s -> %s (pinned via --resolve)\n", host, ip)
+		return
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		fmt.Printf("Warning: could not resolve %s: %v\n", host, err)
+		return
+	}
+	addrs := make([]string, len(ips))
+	for i, ip := range ips {
+		addrs[i] = ip.String()
+	}
+	fmt.Printf("Resolved %s -> %s\n", host, strings.Join(addrs, ", "))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewHTTPClientInjectsHeaders(t *testing.T) {
+	var gotAuth, gotCustom string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotCustom = r.Header.Get("X-Custom")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client, err := newHTTPClient(transportConfig{
+		Headers: headerFlag{"Authorization: Bearer abc", "X-Custom: yes"},
+	})
+	if err != nil {
+		t.Fatalf("newHTTPClient: %v", err)
+	}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+
+	if gotAuth != "Bearer abc" {
+		t.Errorf("Authorization header = %q, want %q", gotAuth, "Bearer abc")
+	}
+	if gotCustom != "yes" {
+		t.Errorf("X-Custom header = %q, want %q", gotCustom, "yes")
+	}
+}
+
+func TestNewHTTPClientNoHeadersIsPlainTransport(t *testing.T) {
+	client, err := newHTTPClient(transportConfig{})
+	if err != nil {
+		t.Fatalf("newHTTPClient: %v", err)
+	}
+	if _, ok := client.Transport.(*http.Transport); !ok {
+		t.Errorf("Transport = %T, want *http.Transport when no headers are configured", client.Transport)
+	}
+}
+
+func TestNewHTTPClientInvalidProxy(t *testing.T) {
+	if _, err := newHTTPClient(transportConfig{Proxy: "://not-a-url"}); err == nil {
+		t.Error("expected an error for an invalid --proxy URL")
+	}
+}
+
+func TestHeaderFlagSetRequiresColon(t *testing.T) {
+	var h headerFlag
+	if err := h.Set("no-colon-here"); err == nil {
+		t.Error("expected an error for a header value without a colon")
+	}
+	if err := h.Set("K:V"); err != nil {
+		t.Errorf("Set(\"K:V\"): %v", err)
+	}
+}
+
+func TestResolveFlagSetRequiresHostAndIP(t *testing.T) {
+	r := resolveFlag{}
+	if err := r.Set("missing-ip:"); err == nil {
+		t.Error("expected an error when ip is empty")
+	}
+	if err := r.Set("example.com:1.2.3.4"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if r["example.com"] != "1.2.3.4" {
+		t.Errorf("r[\"example.com\"] = %q, want %q", r["example.com"], "1.2.3.4")
+	}
+}